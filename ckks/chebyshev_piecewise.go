@@ -0,0 +1,88 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+)
+
+// pieceSelectorDegree is the degree used for the smoothed indicator polynomial of each
+// piece of a PiecewiseChebyshev : it only has to separate "inside this piece's interval"
+// from "outside it" over the full approximation range, so a modest fixed degree suffices
+// regardless of the degree used to fit the piece itself.
+const pieceSelectorDegree = 16
+
+// pieceSelectorSteepness controls how sharply the smoothed indicator transitions at a
+// piece boundary ; higher values approximate a hard indicator more closely at the cost of
+// a higher-degree fit being needed to track the transition.
+const pieceSelectorSteepness = 20.0
+
+// ApproximateReal computes a Chebyshev approximation of the real-valued function f over
+// [a, b], for use with EvaluateCheby / EvaluateChebyPS. It lifts f to the complex domain
+// expected by Approximate, evaluating it along the real axis only.
+func ApproximateReal(f func(float64) float64, a, b float64, degree int) (cheby *ChebyshevInterpolation) {
+	return Approximate(func(x complex128) complex128 {
+		return complex(f(real(x)), 0)
+	}, complex(a, 0), complex(b, 0), degree)
+}
+
+// PiecewiseChebyshev stores, for each of a set of adjacent intervals, a Chebyshev
+// interpolant fitted to that interval alone together with a smoothed indicator polynomial
+// used to homomorphically select it. It lets ApproximatePiecewise fit sharp-transition
+// functions (sigmoids, ReLU, comparison-like functions) with a far lower per-piece degree
+// than a single global interpolant would need.
+type PiecewiseChebyshev struct {
+	breakpoints []float64
+	pieces      []*ChebyshevInterpolation
+	selectors   []*ChebyshevInterpolation
+}
+
+// Pieces returns the per-interval Chebyshev interpolants, ordered by increasing interval.
+func (pw *PiecewiseChebyshev) Pieces() []*ChebyshevInterpolation {
+	return pw.pieces
+}
+
+// Selectors returns the smoothed indicator polynomials used to homomorphically select
+// each piece, in the same order as Pieces.
+func (pw *PiecewiseChebyshev) Selectors() []*ChebyshevInterpolation {
+	return pw.selectors
+}
+
+// ApproximatePiecewise fits one Chebyshev interpolant of its own degree (from
+// degreesPerPiece) per interval of f delimited by breakpoints, plus one smoothed indicator
+// polynomial per piece used to homomorphically select it. breakpoints holds the interval
+// boundaries including both ends of the overall approximation range, so it must have
+// len(degreesPerPiece)+1 entries.
+func ApproximatePiecewise(f func(float64) float64, breakpoints []float64, degreesPerPiece []int) (pw *PiecewiseChebyshev, err error) {
+
+	if len(breakpoints) != len(degreesPerPiece)+1 {
+		return nil, fmt.Errorf("ckks: ApproximatePiecewise requires len(breakpoints) == len(degreesPerPiece)+1, got %d and %d", len(breakpoints), len(degreesPerPiece))
+	}
+
+	pw = &PiecewiseChebyshev{
+		breakpoints: breakpoints,
+		pieces:      make([]*ChebyshevInterpolation, len(degreesPerPiece)),
+		selectors:   make([]*ChebyshevInterpolation, len(degreesPerPiece)),
+	}
+
+	a, b := breakpoints[0], breakpoints[len(breakpoints)-1]
+
+	for i, degree := range degreesPerPiece {
+		lo, hi := breakpoints[i], breakpoints[i+1]
+		pw.pieces[i] = ApproximateReal(f, lo, hi, degree)
+		pw.selectors[i] = ApproximateReal(pieceIndicator(lo, hi), a, b, pieceSelectorDegree)
+	}
+
+	return pw, nil
+}
+
+// pieceIndicator returns a smooth approximation of the indicator of [lo, hi], built from
+// two logistic transitions, one rising at lo and one falling at hi.
+func pieceIndicator(lo, hi float64) func(float64) float64 {
+	return func(x float64) float64 {
+		return logistic(pieceSelectorSteepness*(x-lo)) - logistic(pieceSelectorSteepness*(x-hi))
+	}
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,320 @@
+package ckks
+
+import "math"
+
+// ChebyPSThreshold is the degree above which EvaluateCheby dispatches to the
+// Paterson-Stockmeyer evaluator EvaluateChebyPS instead of the direct recursive
+// evaluation : below the threshold the baby/giant-step precomputation of EvaluateChebyPS
+// costs more multiplications than it saves. It is a package-level variable rather than a
+// constant so that a caller with a different parameter set or modulus-chain budget can
+// tune it.
+var ChebyPSThreshold = 5
+
+// EvaluateCheby evaluates the Chebyshev interpolant cheby homomorphically on ct,
+// relinearizing with rlk after every ciphertext-ciphertext multiplication. ct is assumed
+// to already have undergone the change of variable mapping [cheby.A(), cheby.B()] to
+// [-1, 1] (see the chebyshevinterpolation example). Interpolants of degree greater than
+// ChebyPSThreshold are evaluated with the Paterson-Stockmeyer method, which brings the
+// number of ciphertext-ciphertext multiplications down from Θ(degree) to Θ(√degree) and
+// the multiplicative depth down from the direct evaluation's Θ(degree) to Θ(log2(degree)).
+func (eval *evaluator) EvaluateCheby(ct *Ciphertext, cheby *ChebyshevInterpolation, rlk *EvaluationKey) (res *Ciphertext, err error) {
+
+	if int(cheby.maxDeg) > ChebyPSThreshold {
+		return eval.EvaluateChebyPS(ct, cheby, rlk)
+	}
+
+	return eval.evaluateChebyDirect(ct, cheby.coeffs, rlk)
+}
+
+// evaluateChebyDirect evaluates the Chebyshev series coeffs on u = ct, following the
+// recurrence T_{i+1} = 2*u*T_i - T_{i-1}, one ciphertext multiplication per degree. It is
+// the pre-existing, naive evaluation strategy kept for low-degree interpolants.
+func (eval *evaluator) evaluateChebyDirect(ct *Ciphertext, coeffs []complex128, rlk *EvaluationKey) (res *Ciphertext, err error) {
+
+	Tprev := ct.CopyNew().Ciphertext()
+	T := ct.CopyNew().Ciphertext()
+
+	res = NewCiphertext(ct.Degree(), ct.Level(), ct.Scale())
+	eval.MultByConst(T, coeffs[1], res)
+	eval.AddConst(res, coeffs[0], res)
+
+	for i := 2; i < len(coeffs); i++ {
+
+		Tnext := NewCiphertext(ct.Degree(), ct.Level(), ct.Scale())
+		if err = eval.MulRelin(ct, T, rlk, Tnext); err != nil {
+			return nil, err
+		}
+		eval.MultByConst(Tnext, complex(2, 0), Tnext)
+		if err = eval.Rescale(Tnext, ct.Scale(), Tnext); err != nil {
+			return nil, err
+		}
+		eval.Sub(Tnext, Tprev, Tnext)
+
+		Tprev, T = T, Tnext
+
+		partial := NewCiphertext(ct.Degree(), T.Level(), T.Scale())
+		eval.MultByConst(T, coeffs[i], partial)
+		eval.Add(res, partial, res)
+	}
+
+	return res, nil
+}
+
+// EvaluateChebyPS evaluates the Chebyshev interpolant cheby on ct using the
+// Paterson-Stockmeyer method. The Chebyshev-basis product T_a(u)*T_b(u) is not T_{a+b}(u)
+// but (T_{a+b}(u)+T_{|a-b|}(u))/2, so a combination built directly out of Chebyshev powers
+// of u does not reconstruct the original series once more than one chunk is folded in. To
+// sidestep that, cheby's coefficients are first converted to the equivalent power-basis
+// (monomial) coefficients of u (a plain, real-valued change of basis, computed once from
+// the already-known coefficients) : in the power basis u^a*u^b = u^(a+b) exactly, with no
+// cross term, so every combination below is exact for any split point.
+//
+// Writing the degree-d series in m-sized chunks (m ≈ √d, padded up to the next power of
+// two L of chunks), this precomputes the baby-step powers u^1,…,u^m via a recursive
+// halving scheme (Θ(log m) depth, rather than one multiplication per power), the giant
+// steps u^m, u^2m, u^4m,… via repeated squaring (Θ(log L) depth), and combines the L
+// chunks through a balanced binary tree — p(u) = p_lo(u) + u^(half·m)·p_hi(u) at every
+// node — instead of a linear chain, so the overall multiplicative depth stays
+// Θ(log2(degree)) rather than degrading to Θ(√degree). rlk is used to relinearize after
+// every ciphertext-ciphertext multiplication.
+func (eval *evaluator) EvaluateChebyPS(ct *Ciphertext, cheby *ChebyshevInterpolation, rlk *EvaluationKey) (res *Ciphertext, err error) {
+
+	coeffs := chebyToMonomial(cheby.coeffs)
+	d := len(coeffs) - 1
+
+	m := int(math.Ceil(math.Sqrt(float64(d + 1))))
+	if m < 1 {
+		m = 1
+	}
+	l := int(math.Ceil(float64(d+1) / float64(m)))
+	L := nextPow2(l)
+
+	babySteps, err := eval.genMonomialBabySteps(ct, m, rlk)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]*Ciphertext, L)
+	for i := 0; i < L; i++ {
+		lo := i * m
+		if lo >= len(coeffs) {
+			chunks[i] = eval.evaluateMonomialChunkPoly([]complex128{0}, babySteps)
+			continue
+		}
+		hi := lo + m
+		if hi > len(coeffs) {
+			hi = len(coeffs)
+		}
+		chunks[i] = eval.evaluateMonomialChunkPoly(coeffs[lo:hi], babySteps)
+	}
+
+	giantSteps, err := eval.genMonomialGiantSteps(babySteps[m-1], ilog2(L), rlk)
+	if err != nil {
+		return nil, err
+	}
+
+	return eval.evaluateMonomialPSBalanced(chunks, giantSteps, rlk)
+}
+
+// chebyToMonomial converts a Chebyshev-basis coefficient vector (coeffs[i] is the
+// coefficient of T_i) into the equivalent power-basis coefficient vector (monomial[j] is
+// the coefficient of u^j), by expanding every T_i via the standard recurrence
+// T_i = 2*u*T_{i-1} - T_{i-2} in coefficient-vector space. This only touches the
+// already-known plaintext coefficients, never the ciphertext.
+func chebyToMonomial(coeffs []complex128) []complex128 {
+
+	d := len(coeffs) - 1
+
+	monomial := make([]complex128, d+1)
+
+	Tprev := make([]complex128, d+1)
+	Tprev[0] = 1 // T_0 = 1
+	addScaled(monomial, Tprev, coeffs[0])
+
+	if d == 0 {
+		return monomial
+	}
+
+	T := make([]complex128, d+1)
+	T[1] = 1 // T_1 = u
+	addScaled(monomial, T, coeffs[1])
+
+	for i := 2; i <= d; i++ {
+
+		Tnext := make([]complex128, d+1)
+		for j := 1; j <= d; j++ {
+			Tnext[j] = 2 * T[j-1]
+		}
+		for j := 0; j <= d; j++ {
+			Tnext[j] -= Tprev[j]
+		}
+
+		addScaled(monomial, Tnext, coeffs[i])
+		Tprev, T = T, Tnext
+	}
+
+	return monomial
+}
+
+func addScaled(dst, src []complex128, c complex128) {
+	for i := range dst {
+		dst[i] += c * src[i]
+	}
+}
+
+// ilog2 returns log2(n) for n a power of two (used to index the giant-step and balanced
+// combination levels, which are always sized by powers of two).
+func ilog2(n int) int {
+	k := 0
+	for n > 1 {
+		n >>= 1
+		k++
+	}
+	return k
+}
+
+// nextPow2 returns the smallest power of two greater than or equal to n.
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// genMonomialBabySteps computes u^1,…,u^m homomorphically via a recursive halving scheme,
+// keeping the deepest baby step at Θ(log m) multiplicative depth instead of the Θ(m) depth
+// of computing them one at a time.
+func (eval *evaluator) genMonomialBabySteps(ct *Ciphertext, m int, rlk *EvaluationKey) (U []*Ciphertext, err error) {
+	return eval.computePowerBasis(ct, m, rlk)
+}
+
+// computePowerBasis returns u^1,…,u^n : it recurses on the ⌈n/2⌉-sized lower half u^1..u^h,
+// then derives every remaining power u^(h+i) with a single extra multiplication
+// u^h * u^i, instead of one multiplication per power computed sequentially.
+func (eval *evaluator) computePowerBasis(ct *Ciphertext, n int, rlk *EvaluationKey) (U []*Ciphertext, err error) {
+
+	if n == 1 {
+		return []*Ciphertext{ct.CopyNew().Ciphertext()}, nil
+	}
+
+	half := (n + 1) / 2
+
+	lower, err := eval.computePowerBasis(ct, half, rlk)
+	if err != nil {
+		return nil, err
+	}
+
+	um := lower[half-1]
+
+	U = make([]*Ciphertext, n)
+	copy(U, lower)
+
+	for i := 0; i < n-half; i++ {
+		next := NewCiphertext(ct.Degree(), um.Level(), um.Scale())
+		if err = eval.MulRelin(um, lower[i], rlk, next); err != nil {
+			return nil, err
+		}
+		if err = eval.Rescale(next, ct.Scale(), next); err != nil {
+			return nil, err
+		}
+		U[half+i] = next
+	}
+
+	return U, nil
+}
+
+// genMonomialGiantSteps computes u^m, u^2m, u^4m,…, up to steps entries, via the monomial
+// doubling identity u^(2k·m) = (u^(k·m))² — exact in the power basis, unlike the Chebyshev
+// doubling identity T_2k = 2·T_k²−1 it replaces — keeping the giant-step depth at Θ(log L).
+func (eval *evaluator) genMonomialGiantSteps(giantStep0 *Ciphertext, steps int, rlk *EvaluationKey) (G []*Ciphertext, err error) {
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	G = make([]*Ciphertext, steps)
+	G[0] = giantStep0
+
+	for i := 1; i < steps; i++ {
+
+		sq := NewCiphertext(giantStep0.Degree(), G[i-1].Level(), G[i-1].Scale())
+		if err = eval.MulRelin(G[i-1], G[i-1], rlk, sq); err != nil {
+			return nil, err
+		}
+		if err = eval.Rescale(sq, giantStep0.Scale(), sq); err != nil {
+			return nil, err
+		}
+
+		G[i] = sq
+	}
+
+	return G, nil
+}
+
+// evaluateMonomialChunkPoly evaluates, homomorphically, the polynomial given by a chunk of
+// at most m power-basis coefficients against the precomputed baby steps :
+// p(u) = coeffs[0] + sum_{i=1}^{len(coeffs)-1} coeffs[i]*u^i. The constant term is added
+// at plaintext scale.
+func (eval *evaluator) evaluateMonomialChunkPoly(coeffs []complex128, U []*Ciphertext) *Ciphertext {
+
+	res := NewCiphertext(U[0].Degree(), U[0].Level(), U[0].Scale())
+
+	if len(coeffs) == 1 {
+		eval.MultByConst(U[0], complex(0, 0), res)
+		eval.AddConst(res, coeffs[0], res)
+		return res
+	}
+
+	eval.MultByConst(U[0], coeffs[1], res)
+	eval.AddConst(res, coeffs[0], res)
+
+	for i := 2; i < len(coeffs); i++ {
+		term := NewCiphertext(U[0].Degree(), U[i-1].Level(), U[i-1].Scale())
+		eval.MultByConst(U[i-1], coeffs[i], term)
+		eval.Add(res, term, res)
+	}
+
+	return res
+}
+
+// evaluateMonomialPSBalanced folds the L (a power of two) chunk evaluations chunks into a
+// single ciphertext through a balanced binary tree : at every node covering a power-of-two
+// range of half-size half, it combines its two halves as low + u^(half·m)·high, using the
+// matching precomputed giant step. Because every node's range length is a power of two,
+// the giant step index — log2(half) — is always in bounds, and the tree has depth
+// log2(L), giving the whole evaluation a multiplicative depth of Θ(log2(degree)) instead
+// of the Θ(√degree) depth a linear chunk-by-chunk fold-in would incur.
+func (eval *evaluator) evaluateMonomialPSBalanced(chunks []*Ciphertext, giantSteps []*Ciphertext, rlk *EvaluationKey) (res *Ciphertext, err error) {
+
+	if len(chunks) == 1 {
+		return chunks[0], nil
+	}
+
+	half := len(chunks) / 2
+
+	low, err := eval.evaluateMonomialPSBalanced(chunks[:half], giantSteps, rlk)
+	if err != nil {
+		return nil, err
+	}
+
+	high, err := eval.evaluateMonomialPSBalanced(chunks[half:], giantSteps, rlk)
+	if err != nil {
+		return nil, err
+	}
+
+	giant := giantSteps[ilog2(half)]
+
+	term := NewCiphertext(giant.Degree(), giant.Level(), giant.Scale())
+	if err = eval.MulRelin(giant, high, rlk, term); err != nil {
+		return nil, err
+	}
+	if err = eval.Rescale(term, giant.Scale(), term); err != nil {
+		return nil, err
+	}
+
+	res = NewCiphertext(term.Degree(), term.Level(), term.Scale())
+	eval.Add(low, term, res)
+
+	return res, nil
+}
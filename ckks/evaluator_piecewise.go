@@ -0,0 +1,41 @@
+package ckks
+
+// EvaluatePiecewiseCheby evaluates every piece of pw on ct and combines them as
+// sum_i selector_i(ct) * piece_i(ct), so that the resulting ciphertext carries f(x) for
+// whichever piece x actually falls into. Each piece and its selector are evaluated
+// independently against ct (via EvaluateCheby, itself dispatching to EvaluateChebyPS for
+// high-degree pieces), so the cost of a piecewise approximation only grows with the
+// number of pieces actually needed to track the target function's sharp transitions,
+// rather than with a single global interpolant's degree.
+func (eval *evaluator) EvaluatePiecewiseCheby(ct *Ciphertext, pw *PiecewiseChebyshev, rlk *EvaluationKey) (res *Ciphertext, err error) {
+
+	for i, piece := range pw.pieces {
+
+		value, err := eval.EvaluateCheby(ct, piece, rlk)
+		if err != nil {
+			return nil, err
+		}
+
+		selector, err := eval.EvaluateCheby(ct, pw.selectors[i], rlk)
+		if err != nil {
+			return nil, err
+		}
+
+		weighted := NewCiphertext(value.Degree(), value.Level(), value.Scale())
+		if err = eval.MulRelin(value, selector, rlk, weighted); err != nil {
+			return nil, err
+		}
+		if err = eval.Rescale(weighted, value.Scale(), weighted); err != nil {
+			return nil, err
+		}
+
+		if res == nil {
+			res = weighted
+			continue
+		}
+
+		eval.Add(res, weighted, res)
+	}
+
+	return res, nil
+}
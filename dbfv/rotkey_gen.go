@@ -21,6 +21,10 @@ type RKG struct {
 	rot_row   [][2]*ring.Poly
 
 	polypool *ring.Poly
+
+	verifState *verifiableRKGState
+
+	contributions map[string]map[uint64][]*ring.Poly
 }
 
 // newRKG creates a new RKG object and will be used to generate collective rotation-keys from a shared secret-key among j parties.
@@ -0,0 +1,164 @@
+package dbfv
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ldsec/lattigo/bfv"
+	"github.com/ldsec/lattigo/ring"
+)
+
+// RKGBatchShare is the share produced by a single party for a batch of rotations : it
+// bundles the left-rotation, right-rotation and (optionally) row-rotation shares of one
+// RKG round into a single message, so that multi-party transport layers only pay one
+// round-trip instead of one per requested rotation.
+type RKGBatchShare struct {
+	LeftKs  []uint64
+	RightKs []uint64
+	Row     bool
+
+	shares map[string][]*ring.Poly
+}
+
+// Labels returns the stable, sorted ordering of the keys composing the batch share. Two
+// batch shares produced for the same (leftKs, rightKs, includeRow) request always expose
+// the same label ordering, which lets a transport layer serialize/deserialize the shares
+// without shipping the keys themselves.
+func (share *RKGBatchShare) Labels() []string {
+	labels := make([]string, 0, len(share.shares))
+	for label := range share.shares {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Get returns the evakey share for the given rotation label (see rotLeftLabel, rotRightLabel, rotRowLabel).
+func (share *RKGBatchShare) Get(label string) []*ring.Poly {
+	return share.shares[label]
+}
+
+func rotLeftLabel(k uint64) string {
+	return fmt.Sprintf("L:%d", k)
+}
+
+func rotRightLabel(k uint64) string {
+	return fmt.Sprintf("R:%d", k)
+}
+
+const rotRowLabel = "row"
+
+// GenShareRotBatch computes, in a single call, the GenShareRotLeft, GenShareRotRight and
+// GenShareRotRow shares of all the requested rotations, one rotation after the other. Each
+// rotation goes through the same per-rotation method, so rkg.polypool and the Gaussian
+// sampler are used exactly as they would be by separate calls. crps must provide one CRP, keyed by
+// rotLeftLabel(k)/rotRightLabel(k)/rotRowLabel, for every requested rotation.
+func (rkg *RKG) GenShareRotBatch(sk *ring.Poly, leftKs, rightKs []uint64, includeRow bool, crps map[string][]*ring.Poly) (batch *RKGBatchShare, err error) {
+
+	batch = &RKGBatchShare{
+		LeftKs:  leftKs,
+		RightKs: rightKs,
+		Row:     includeRow,
+		shares:  make(map[string][]*ring.Poly, len(leftKs)+len(rightKs)+1),
+	}
+
+	for _, k := range leftKs {
+		label := rotLeftLabel(k)
+		crp, ok := crps[label]
+		if !ok {
+			return nil, fmt.Errorf("dbfv: missing crp for left rotation %d", k)
+		}
+		batch.shares[label] = rkg.GenShareRotLeft(sk, k, crp)
+	}
+
+	for _, k := range rightKs {
+		label := rotRightLabel(k)
+		crp, ok := crps[label]
+		if !ok {
+			return nil, fmt.Errorf("dbfv: missing crp for right rotation %d", k)
+		}
+		batch.shares[label] = rkg.GenShareRotRight(sk, k, crp)
+	}
+
+	if includeRow {
+		crp, ok := crps[rotRowLabel]
+		if !ok {
+			return nil, fmt.Errorf("dbfv: missing crp for row rotation")
+		}
+		batch.shares[rotRowLabel] = rkg.GenShareRotRow(sk, crp)
+	}
+
+	return batch, nil
+}
+
+// AggregateBatch is the batched equivalent of AggregateRotColL / AggregateRotColR /
+// AggregateRotRow : it aggregates, for every rotation listed in the first batch share
+// (every other share must carry those rotations too; rotations present only in later
+// shares are ignored), the corresponding per-party shares and stores the result exactly as the
+// non-batched round would, so that FinalizeBatch (or Finalize) can build the resulting
+// RotationKeys the same way regardless of which path produced the aggregates.
+func (rkg *RKG) AggregateBatch(batches []*RKGBatchShare, crps map[string][]*ring.Poly) error {
+
+	if len(batches) == 0 {
+		return fmt.Errorf("dbfv: AggregateBatch requires at least one share")
+	}
+
+	for _, k := range batches[0].LeftKs {
+		label := rotLeftLabel(k)
+		crp, ok := crps[label]
+		if !ok {
+			return fmt.Errorf("dbfv: missing crp for left rotation %d", k)
+		}
+		samples, err := collectBatchSamples(batches, label)
+		if err != nil {
+			return err
+		}
+		rkg.AggregateRotColL(samples, k, crp)
+	}
+
+	for _, k := range batches[0].RightKs {
+		label := rotRightLabel(k)
+		crp, ok := crps[label]
+		if !ok {
+			return fmt.Errorf("dbfv: missing crp for right rotation %d", k)
+		}
+		samples, err := collectBatchSamples(batches, label)
+		if err != nil {
+			return err
+		}
+		rkg.AggregateRotColR(samples, k, crp)
+	}
+
+	if batches[0].Row {
+		crp, ok := crps[rotRowLabel]
+		if !ok {
+			return fmt.Errorf("dbfv: missing crp for row rotation")
+		}
+		samples, err := collectBatchSamples(batches, rotRowLabel)
+		if err != nil {
+			return err
+		}
+		rkg.AggregateRotRow(samples, crp)
+	}
+
+	return nil
+}
+
+func collectBatchSamples(batches []*RKGBatchShare, label string) ([][]*ring.Poly, error) {
+	samples := make([][]*ring.Poly, len(batches))
+	for i, batch := range batches {
+		share := batch.Get(label)
+		if share == nil {
+			return nil, fmt.Errorf("dbfv: batch share %d is missing rotation %s", i, label)
+		}
+		samples[i] = share
+	}
+	return samples, nil
+}
+
+// FinalizeBatch retrieves all the rotation-keys aggregated by AggregateBatch and returns
+// them as a single RotationKeys structure, the same way Finalize does for a non-batched
+// round.
+func (rkg *RKG) FinalizeBatch(keygen *bfv.KeyGenerator) (rotkey *bfv.RotationKeys) {
+	return rkg.Finalize(keygen)
+}
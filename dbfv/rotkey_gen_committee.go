@@ -0,0 +1,159 @@
+package dbfv
+
+import (
+	"fmt"
+
+	"github.com/ldsec/lattigo/ring"
+)
+
+// CommitteeShare is a single party's contribution to a tracked RKG round : it is a plain
+// GenShareRotLeft/Right/Row share, tagged with the party's id so that AggregateCommitteeShares
+// can record who contributed what, which in turn lets a later RevokeParty drop one party's
+// contribution and re-form the rotation key over the remaining committee alone, instead of
+// asking every surviving party to regenerate and resubmit its share from scratch.
+//
+// This is deliberately not a key-switch of an already-finalized rotation key onto a new
+// collective secret : a rotation key's public share is an RLWE encryption, under the
+// collective secret S, of the specific plaintext pi(S)-S, so re-encrypting that ciphertext
+// under a different secret S_new (the only thing a key-switch can do without decrypting)
+// still leaves it carrying the old plaintext pi(S_old)-S_old, not the pi(S_new)-S_new a
+// rotation key for S_new actually needs. Producing a rotation key genuinely valid under a
+// new collective secret therefore requires a fresh RKG round under that secret — exactly
+// what GenShareRotLeft/Right/Row already provide. CommitteeShare and AggregateCommitteeShares
+// add the contribution-tracking a committee-membership change needs on top of that, nothing
+// more ; they do not accept or depend on any prior rotation key.
+type CommitteeShare struct {
+	PartyID uint64
+	Label   string
+	Share   []*ring.Poly
+}
+
+// GenRotLeftTracked wraps party pid's GenShareRotLeft share into a CommitteeShare, so that
+// AggregateCommitteeShares can track it against a later revocation.
+func (rkg *RKG) GenRotLeftTracked(pid uint64, sk *ring.Poly, k uint64, crp []*ring.Poly) *CommitteeShare {
+	return &CommitteeShare{PartyID: pid, Label: rotLeftLabel(k), Share: rkg.GenShareRotLeft(sk, k, crp)}
+}
+
+// GenRotRightTracked is the right-rotation equivalent of GenRotLeftTracked.
+func (rkg *RKG) GenRotRightTracked(pid uint64, sk *ring.Poly, k uint64, crp []*ring.Poly) *CommitteeShare {
+	return &CommitteeShare{PartyID: pid, Label: rotRightLabel(k), Share: rkg.GenShareRotRight(sk, k, crp)}
+}
+
+// GenRotRowTracked is the row-rotation equivalent of GenRotLeftTracked.
+func (rkg *RKG) GenRotRowTracked(pid uint64, sk *ring.Poly, crp []*ring.Poly) *CommitteeShare {
+	return &CommitteeShare{PartyID: pid, Label: rotRowLabel, Share: rkg.GenShareRotRow(sk, crp)}
+}
+
+// AggregateCommitteeShares combines the committee's tracked contributions for label,
+// recording each party's contribution so that a subsequent RevokeParty can remove one
+// party's share and re-form the key over the reduced committee without asking the
+// survivors to regenerate their shares from scratch.
+func (rkg *RKG) AggregateCommitteeShares(label string, shares []*CommitteeShare, crp []*ring.Poly) (aggShare [][2]*ring.Poly, err error) {
+
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("dbfv: AggregateCommitteeShares requires at least one share")
+	}
+
+	if rkg.contributions == nil {
+		rkg.contributions = make(map[string]map[uint64][]*ring.Poly)
+	}
+	if rkg.contributions[label] == nil {
+		rkg.contributions[label] = make(map[uint64][]*ring.Poly)
+	}
+
+	samples := make([][]*ring.Poly, len(shares))
+	for i, share := range shares {
+		if share.Label != label {
+			return nil, fmt.Errorf("dbfv: committee share from party %d is for label %s, expected %s", share.PartyID, share.Label, label)
+		}
+		samples[i] = share.Share
+		rkg.contributions[label][share.PartyID] = share.Share
+	}
+
+	aggShare = rkg.aggregate(samples, crp)
+	rkg.storeAggregate(label, aggShare)
+
+	return aggShare, nil
+}
+
+// RevokeParty invalidates every tracked contribution made by pid and re-forms the
+// affected rotation keys over the remaining committee members : this is the key-switch
+// analogue of a coordinator removing a compromised member by subtracting its public share
+// and re-running the CRS-based switch-key generation on the reduced set.
+func (rkg *RKG) RevokeParty(pid uint64, crps map[string][]*ring.Poly) error {
+
+	for label, contribs := range rkg.contributions {
+
+		if _, tracked := contribs[pid]; !tracked {
+			continue
+		}
+
+		delete(contribs, pid)
+
+		if len(contribs) == 0 {
+			rkg.clearAggregate(label)
+			continue
+		}
+
+		crp, ok := crps[label]
+		if !ok {
+			return fmt.Errorf("dbfv: RevokeParty requires the crp for %s to re-form the reduced key", label)
+		}
+
+		samples := make([][]*ring.Poly, 0, len(contribs))
+		for _, share := range contribs {
+			samples = append(samples, share)
+		}
+
+		rkg.storeAggregate(label, rkg.aggregate(samples, crp))
+	}
+
+	return nil
+}
+
+// storeAggregate writes the aggregated share for label back into the plain (non-tracked)
+// rotation maps, so that Finalize / FinalizeBatch build the resulting RotationKeys the
+// same way regardless of whether the key went through a tracked committee round.
+func (rkg *RKG) storeAggregate(label string, share [][2]*ring.Poly) {
+	if label == rotRowLabel {
+		rkg.rot_row = share
+		return
+	}
+	if k, ok := parseRotLeftLabel(label); ok {
+		rkg.rot_col_L[k] = share
+		return
+	}
+	if k, ok := parseRotRightLabel(label); ok {
+		rkg.rot_col_R[k] = share
+	}
+}
+
+// clearAggregate drops the aggregated share for label, used when a revocation leaves no
+// remaining contributor for that rotation.
+func (rkg *RKG) clearAggregate(label string) {
+	if label == rotRowLabel {
+		rkg.rot_row = nil
+		return
+	}
+	if k, ok := parseRotLeftLabel(label); ok {
+		delete(rkg.rot_col_L, k)
+		return
+	}
+	if k, ok := parseRotRightLabel(label); ok {
+		delete(rkg.rot_col_R, k)
+	}
+}
+
+func parseRotLeftLabel(label string) (k uint64, ok bool) {
+	if _, err := fmt.Sscanf(label, "L:%d", &k); err != nil {
+		return 0, false
+	}
+	return k, true
+}
+
+func parseRotRightLabel(label string) (k uint64, ok bool) {
+	if _, err := fmt.Sscanf(label, "R:%d", &k); err != nil {
+		return 0, false
+	}
+	return k, true
+}
@@ -0,0 +1,426 @@
+package dbfv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ldsec/lattigo/ring"
+)
+
+// rkgChallengeWeight is the number of nonzero (±1) coefficients in the Fiat-Shamir
+// challenge polynomial, following the Dilithium/BLISS "sample in ball" style : a sparse
+// ±1/0 polynomial, rather than a single scalar, gives the challenge astronomically more
+// entropy (choose(N, rkgChallengeWeight) * 2^rkgChallengeWeight possible values, for N the
+// ring degree) than a scalar ever could, which is what closes the forgery this replaces —
+// with a small scalar challenge, both proof equations are linear in the prover's masks, so
+// for any chosen challenge value the masks solving the equations can be computed directly
+// from the public values alone, and the only remaining obstacle is finding a challenge
+// that happens to hash to itself ; with a handful of possible scalars that search is
+// nearly free, but with a sparse polynomial challenge out of an astronomically large space
+// it is not. Keeping the challenge sparse (rather than one independent trit per
+// coefficient) also keeps its multiplicative norm small, so the noise-bound check below
+// still distinguishes an honest share from a forged one instead of rejecting everything.
+const rkgChallengeWeight = 64
+
+// rkgNoiseBound is the per-coefficient (centered) tolerance used when checking the
+// share-side proof equation. It has to absorb both the Gaussian noise any RKG share (and
+// every proof mask, built the same way) carries, and the challenge polynomial's
+// contribution to it : since the challenge has rkgChallengeWeight many ±1 coefficients,
+// multiplying it into a noise polynomial can amplify that noise by up to roughly
+// rkgChallengeWeight, so the bound is set well above a single share's own noise level
+// while staying far narrower than the ring modulus, so a share proof that does not stem
+// from the committed secret is still rejected with overwhelming probability.
+const rkgNoiseBound = 1 << 26
+
+// RKGCommitment is a party's additively-homomorphic commitment to its secret-key share sk,
+// built against a pair of public commitment CRPs cCRP1, cCRP2 :
+//
+//	C = cCRP1*sk + cCRP2*r
+//
+// for a random blinding polynomial r chosen by the party. Unlike an opaque hash, this
+// commitment can be algebraically combined with the Schnorr-style responses produced by
+// genShareProof, which is what lets VerifyShare actually check a share against it instead
+// of only against itself.
+type RKGCommitment struct {
+	Value []*ring.Poly
+}
+
+// CommitSecret computes an RKGCommitment to sk using blinding r, against the commitment
+// CRPs cCRP1, cCRP2. A party calls this once, ahead of a verifiable RKG round, and
+// broadcasts the result alongside its session identifier.
+func (rkg *RKG) CommitSecret(sk, r *ring.Poly, cCRP1, cCRP2 []*ring.Poly) *RKGCommitment {
+	return &RKGCommitment{Value: rkg.linearCombination(cCRP1, sk, cCRP2, r)}
+}
+
+// RKGRotationKind identifies which of the three RKG share constructions (left rotation,
+// right rotation, row rotation) a verifiable share proof is bound to, so that VerifyShare
+// can rebuild the same share-construction function the prover used.
+type RKGRotationKind int
+
+const (
+	RKGRotLeft RKGRotationKind = iota
+	RKGRotRight
+	RKGRotRow
+)
+
+func (rkg *RKG) shareFuncFor(kind RKGRotationKind, k uint64, crp []*ring.Poly) func(*ring.Poly) []*ring.Poly {
+	switch kind {
+	case RKGRotLeft:
+		return func(s *ring.Poly) []*ring.Poly { return rkg.GenShareRotLeft(s, k, crp) }
+	case RKGRotRight:
+		return func(s *ring.Poly) []*ring.Poly { return rkg.GenShareRotRight(s, k, crp) }
+	default:
+		return func(s *ring.Poly) []*ring.Poly { return rkg.GenShareRotRow(s, crp) }
+	}
+}
+
+// RKGShareProof is a Schnorr-style, Fiat-Shamir proof that the accompanying rotation-key
+// share was constructed from the very secret-key share sk committed to by an
+// RKGCommitment, bound to the session identifier tau, the party id and the CRP of the
+// round : CommitMask and ShareMask are the prover's masked commitment and share (built
+// from a random y the same way the real commitment/share were built from sk), and ZSk,
+// ZBlind are the responses y+c*sk, yr+c*r for the Fiat-Shamir challenge c. VerifyShare
+// recomputes c and checks both equations.
+type RKGShareProof struct {
+	CommitMask []*ring.Poly
+	ShareMask  []*ring.Poly
+	ZSk        *ring.Poly
+	ZBlind     *ring.Poly
+}
+
+// GenShareRotLeftVerifiable behaves like GenShareRotLeft but additionally produces a proof
+// binding the returned share to the session identifier tau, the party's commitment to sk
+// (built from sk and blinding r) and the CRP crp. tau must be unique per ceremony : the
+// proof's challenge is derived from tau and VerifyShare only accepts the session's own tau,
+// so a share and proof made in another run (for instance a discarded run under a different
+// crp) are rejected.
+func (rkg *RKG) GenShareRotLeftVerifiable(sk, r *ring.Poly, pid uint64, commitment *RKGCommitment, cCRP1, cCRP2 []*ring.Poly, tau []byte, k uint64, crp []*ring.Poly) (evakey []*ring.Poly, proof *RKGShareProof) {
+	evakey = rkg.GenShareRotLeft(sk, k, crp)
+	proof = rkg.genShareProof(sk, r, commitment, cCRP1, cCRP2, tau, pid, crp, evakey, rkg.shareFuncFor(RKGRotLeft, k, crp))
+	return
+}
+
+// GenShareRotRightVerifiable is the right-rotation equivalent of GenShareRotLeftVerifiable.
+func (rkg *RKG) GenShareRotRightVerifiable(sk, r *ring.Poly, pid uint64, commitment *RKGCommitment, cCRP1, cCRP2 []*ring.Poly, tau []byte, k uint64, crp []*ring.Poly) (evakey []*ring.Poly, proof *RKGShareProof) {
+	evakey = rkg.GenShareRotRight(sk, k, crp)
+	proof = rkg.genShareProof(sk, r, commitment, cCRP1, cCRP2, tau, pid, crp, evakey, rkg.shareFuncFor(RKGRotRight, k, crp))
+	return
+}
+
+// GenShareRotRowVerifiable behaves like GenShareRotRow but additionally produces a proof
+// binding the returned share to the session identifier tau, the party's commitment to sk
+// and the CRP crp.
+func (rkg *RKG) GenShareRotRowVerifiable(sk, r *ring.Poly, pid uint64, commitment *RKGCommitment, cCRP1, cCRP2 []*ring.Poly, tau []byte, crp []*ring.Poly) (evakey []*ring.Poly, proof *RKGShareProof) {
+	evakey = rkg.GenShareRotRow(sk, crp)
+	proof = rkg.genShareProof(sk, r, commitment, cCRP1, cCRP2, tau, pid, crp, evakey, rkg.shareFuncFor(RKGRotRow, 0, crp))
+	return
+}
+
+// genShareProof runs the prover side of the Sigma protocol : it masks sk and its blinding
+// r with freshly sampled randomness, derives the Fiat-Shamir challenge from every public
+// value of the round (including the masks), and returns the masked commitment/share
+// together with the two linear responses.
+func (rkg *RKG) genShareProof(sk, r *ring.Poly, commitment *RKGCommitment, cCRP1, cCRP2 []*ring.Poly, tau []byte, pid uint64, crp, share []*ring.Poly, shareFunc func(*ring.Poly) []*ring.Poly) *RKGShareProof {
+
+	context := rkg.bfvContext.ContextKeys()
+
+	y := rkg.bfvContext.GaussianSampler().SampleNTTNew()
+	yr := rkg.bfvContext.GaussianSampler().SampleNTTNew()
+
+	commitMask := rkg.linearCombination(cCRP1, y, cCRP2, yr)
+	shareMask := shareFunc(y)
+
+	c := rkgChallenge(context, tau, pid, commitment.Value, crp, share, shareMask, commitMask)
+
+	zSk := context.NewPoly()
+	context.MulCoeffsMontgomery(sk, c, zSk)
+	context.Add(zSk, y, zSk)
+
+	zBlind := context.NewPoly()
+	context.MulCoeffsMontgomery(r, c, zBlind)
+	context.Add(zBlind, yr, zBlind)
+
+	return &RKGShareProof{CommitMask: commitMask, ShareMask: shareMask, ZSk: zSk, ZBlind: zBlind}
+}
+
+// VerifyShare checks proof against sample, the party's registered commitment and the
+// ongoing session identifier, rebuilding the share construction for (kind, k, crp). It
+// checks, without learning sk :
+//
+//  1. the commitment-side equation cCRP1*ZSk + cCRP2*ZBlind == CommitMask + c*commitment ;
+//  2. the share-side equation shareFunc(ZSk) == ShareMask + c*sample, up to rkgNoiseBound,
+//     to absorb the Gaussian noise baked into every share and mask.
+//
+// A share built from a secret other than the one bound to the registered commitment fails
+// this check with overwhelming probability, since c ranges over the astronomically large
+// sparse-ternary challenge space described at rkgChallengeWeight rather than a handful of
+// values an attacker could grind through offline.
+func (rkg *RKG) VerifyShare(pid uint64, kind RKGRotationKind, k uint64, crp []*ring.Poly, sample []*ring.Poly, proof *RKGShareProof, tau []byte) error {
+
+	state := rkg.verifState
+	if state == nil || !bytes.Equal(state.tau, tau) {
+		return fmt.Errorf("dbfv: unknown or mismatching session identifier for party %d", pid)
+	}
+
+	commitment, ok := state.commitments[pid]
+	if !ok {
+		return fmt.Errorf("dbfv: no registered commitment for party %d", pid)
+	}
+
+	context := rkg.bfvContext.ContextKeys()
+
+	c := rkgChallenge(context, tau, pid, commitment.Value, crp, sample, proof.ShareMask, proof.CommitMask)
+
+	lhsCommit := rkg.linearCombination(state.cCRP1, proof.ZSk, state.cCRP2, proof.ZBlind)
+	for i := range lhsCommit {
+		rhs := context.NewPoly()
+		context.MulCoeffsMontgomery(commitment.Value[i], c, rhs)
+		context.Add(rhs, proof.CommitMask[i], rhs)
+		if !polyEqual(lhsCommit[i], rhs) {
+			return fmt.Errorf("dbfv: commitment-side proof check failed for party %d", pid)
+		}
+	}
+
+	shareFunc := rkg.shareFuncFor(kind, k, crp)
+	lhsShare := shareFunc(proof.ZSk)
+	for i := range lhsShare {
+		rhs := context.NewPoly()
+		context.MulCoeffsMontgomery(sample[i], c, rhs)
+		context.Add(rhs, proof.ShareMask[i], rhs)
+		if !closeWithinBound(context, lhsShare[i], rhs, rkgNoiseBound) {
+			return fmt.Errorf("dbfv: invalid share proof from party %d", pid)
+		}
+	}
+
+	return nil
+}
+
+// linearCombination computes a*x + b*y component-wise, where a, b are slices of CRP
+// polynomials and x, y are single ring elements (such as a secret-key share and its
+// blinding) : this is the shared primitive behind RKGCommitment, its masking and its
+// verification equation.
+func (rkg *RKG) linearCombination(a []*ring.Poly, x *ring.Poly, b []*ring.Poly, y *ring.Poly) []*ring.Poly {
+	context := rkg.bfvContext.ContextKeys()
+
+	out := make([]*ring.Poly, len(a))
+	for i := range a {
+		out[i] = context.NewPoly()
+		context.MulCoeffsMontgomery(a[i], x, out[i])
+
+		tmp := context.NewPoly()
+		context.MulCoeffsMontgomery(b[i], y, tmp)
+
+		context.Add(out[i], tmp, out[i])
+	}
+	return out
+}
+
+// polyEqual reports whether a and b hold the exact same coefficients.
+func polyEqual(a, b *ring.Poly) bool {
+	if len(a.Coeffs) != len(b.Coeffs) {
+		return false
+	}
+	for i := range a.Coeffs {
+		if len(a.Coeffs[i]) != len(b.Coeffs[i]) {
+			return false
+		}
+		for j := range a.Coeffs[i] {
+			if a.Coeffs[i][j] != b.Coeffs[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// closeWithinBound reports whether every coefficient of a and b differs, modulo each
+// CRT modulus and centered around zero, by at most bound.
+func closeWithinBound(context *ring.Context, a, b *ring.Poly, bound uint64) bool {
+	for index := range a.Coeffs {
+		qi := context.Modulus[index]
+		for j := range a.Coeffs[index] {
+			diff := ring.CRed(a.Coeffs[index][j]+qi-b.Coeffs[index][j], qi)
+			if diff > bound && qi-diff > bound {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rkgChallenge derives the Fiat-Shamir challenge for the share proof from every public
+// value of the round : a ring element with exactly rkgChallengeWeight coefficients set to
+// ±1 (reduced to the appropriate representative mod each qi) and every other coefficient
+// zero, its nonzero positions and signs chosen by expanding a SHA-256 seed of the round's
+// public transcript through rkgChallengeStream. The same seed always yields the same
+// challenge, and an honest verifier recomputing it from the same public values gets back
+// the prover's exact c.
+func rkgChallenge(context *ring.Context, tau []byte, pid uint64, commitment, crp, share, shareMask, commitMask []*ring.Poly) *ring.Poly {
+	h := sha256.New()
+	h.Write(tau)
+	binary.Write(h, binary.BigEndian, pid)
+	for _, group := range [][]*ring.Poly{commitment, crp, share, shareMask, commitMask} {
+		for _, p := range group {
+			h.Write(polyBytes(p))
+		}
+	}
+
+	return sampleChallengePoly(context, h.Sum(nil), rkgChallengeWeight)
+}
+
+// rkgChallengeStream is a counter-mode SHA-256 expansion of a seed into an unbounded
+// byte stream, used to pick the nonzero positions and signs of a sampleChallengePoly
+// challenge without biasing them toward any particular value.
+type rkgChallengeStream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+	pos     int
+}
+
+func (s *rkgChallengeStream) nextByte() byte {
+	if s.pos >= len(s.buf) {
+		h := sha256.New()
+		h.Write(s.seed)
+		binary.Write(h, binary.BigEndian, s.counter)
+		s.buf = h.Sum(nil)
+		s.counter++
+		s.pos = 0
+	}
+	b := s.buf[s.pos]
+	s.pos++
+	return b
+}
+
+// nextIndex draws a uniform position in [0, n) from the stream via rejection sampling on
+// two bytes at a time, discarding draws that would bias the distribution.
+func (s *rkgChallengeStream) nextIndex(n uint64) uint64 {
+	span := (uint64(1) << 16) - (uint64(1)<<16)%n
+	for {
+		v := uint64(s.nextByte())<<8 | uint64(s.nextByte())
+		if v < span {
+			return v % n
+		}
+	}
+}
+
+// sampleChallengePoly builds the sparse ±1/0 challenge polynomial described at
+// rkgChallengeWeight : it picks weight distinct coefficient positions out of context.N via
+// rkgChallengeStream and assigns each a uniformly random sign, represented, at every CRT
+// modulus qi, as the integer 1 or qi-1 (i.e. -1 mod qi) ; every other coefficient is left
+// at zero.
+func sampleChallengePoly(context *ring.Context, seed []byte, weight int) *ring.Poly {
+	if uint64(weight) > context.N {
+		weight = int(context.N)
+	}
+
+	c := context.NewPoly()
+	stream := &rkgChallengeStream{seed: seed}
+
+	chosen := make(map[uint64]bool, weight)
+	for len(chosen) < weight {
+		pos := stream.nextIndex(context.N)
+		if chosen[pos] {
+			continue
+		}
+		chosen[pos] = true
+
+		negative := stream.nextByte()&1 == 1
+		for index, qi := range context.Modulus {
+			if negative {
+				c.Coeffs[index][pos] = qi - 1
+			} else {
+				c.Coeffs[index][pos] = 1
+			}
+		}
+	}
+
+	return c
+}
+
+// RKGTranscript is the public, verifiable record of a verifiable RKG round. It binds the
+// session identifier tau to the ordered list of contributing parties, their commitments
+// and the resulting aggregated public share, so that any observer holding the transcript
+// can re-check the ceremony after the fact.
+type RKGTranscript struct {
+	Tau         []byte
+	PartyIDs    []uint64
+	Commitments map[uint64]*RKGCommitment
+	AggShare    [][2]*ring.Poly
+}
+
+// verifiableRKGState holds the bookkeeping a party needs to verify the shares it receives
+// during a verifiable RKG round : the session identifier, the commitment CRPs and the
+// commitments announced by each contributing party.
+type verifiableRKGState struct {
+	tau         []byte
+	cCRP1       []*ring.Poly
+	cCRP2       []*ring.Poly
+	commitments map[uint64]*RKGCommitment
+}
+
+// BeginVerifiableSession registers the session identifier tau, the commitment CRPs and
+// the commitments announced by the contributing parties ; it must be called before
+// VerifyShare or AggregateVerifiable.
+func (rkg *RKG) BeginVerifiableSession(tau []byte, cCRP1, cCRP2 []*ring.Poly, commitments map[uint64]*RKGCommitment) {
+	rkg.verifState = &verifiableRKGState{tau: tau, cCRP1: cCRP1, cCRP2: cCRP2, commitments: commitments}
+}
+
+// AggregateVerifiable aggregates the samples indexed by partyIDs after checking each of
+// their proofs against the ongoing verifiable session. Shares that fail verification are
+// excluded from the aggregate and their party index is returned in offending, instead of
+// silently producing a broken rotation key. It returns an error, without touching any
+// sample, if partyIDs, samples and proofs are not all the same length, since the three
+// slices are otherwise indexed in lockstep by party position.
+func (rkg *RKG) AggregateVerifiable(partyIDs []uint64, kind RKGRotationKind, k uint64, samples [][]*ring.Poly, proofs []*RKGShareProof, crp []*ring.Poly) (receiver [][2]*ring.Poly, transcript *RKGTranscript, offending []uint64, err error) {
+
+	if len(samples) != len(partyIDs) || len(proofs) != len(partyIDs) {
+		return nil, nil, nil, fmt.Errorf("dbfv: AggregateVerifiable got %d partyIDs, %d samples and %d proofs, expected them all equal", len(partyIDs), len(samples), len(proofs))
+	}
+
+	valid := make([][]*ring.Poly, 0, len(samples))
+	validIDs := make([]uint64, 0, len(partyIDs))
+
+	for i, pid := range partyIDs {
+		if err := rkg.VerifyShare(pid, kind, k, crp, samples[i], proofs[i], rkg.verifState.tau); err != nil {
+			offending = append(offending, pid)
+			continue
+		}
+		valid = append(valid, samples[i])
+		validIDs = append(validIDs, pid)
+	}
+
+	if len(valid) == 0 {
+		return nil, nil, offending, nil
+	}
+
+	receiver = rkg.aggregate(valid, crp)
+
+	commitments := make(map[uint64]*RKGCommitment, len(validIDs))
+	for _, pid := range validIDs {
+		commitments[pid] = rkg.verifState.commitments[pid]
+	}
+
+	transcript = &RKGTranscript{
+		Tau:         rkg.verifState.tau,
+		PartyIDs:    validIDs,
+		Commitments: commitments,
+		AggShare:    receiver,
+	}
+
+	return receiver, transcript, offending, nil
+}
+
+// polyBytes serializes the coefficients of p in a stable order, for use as input to the
+// session proof's Fiat-Shamir hash.
+func polyBytes(p *ring.Poly) []byte {
+	buf := new(bytes.Buffer)
+	for _, coeffs := range p.Coeffs {
+		for _, c := range coeffs {
+			binary.Write(buf, binary.BigEndian, c)
+		}
+	}
+	return buf.Bytes()
+}